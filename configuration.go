@@ -0,0 +1,30 @@
+package kafka
+
+// Configuration bundles the producer- and consumer-side settings used to
+// serialize and deserialize Kafka messages.
+type Configuration struct {
+	Producer ProducerConfiguration `json:"producer"`
+	Consumer ConsumerConfiguration `json:"consumer"`
+}
+
+// ProducerConfiguration configures how produced messages are serialized.
+type ProducerConfiguration struct {
+	ValueSerializer string                      `json:"valueSerializer"`
+	KeySerializer   string                      `json:"keySerializer"`
+	SchemaRegistry  SchemaRegistryConfiguration `json:"schemaRegistry"`
+
+	// EmitTombstones makes SerializeJSON treat a literal JSON null as a Kafka
+	// tombstone: a nil value with no magic-byte prefix, instead of encoding
+	// the four-character string "null". SerializeJSON is the only serializer
+	// that reads it; the Avro and Protobuf serializers ignore it. It defaults
+	// to off, so existing scripts that pass JSON null and expect it encoded as
+	// data keep doing so.
+	EmitTombstones bool `json:"emitTombstones"`
+}
+
+// ConsumerConfiguration configures how consumed messages are deserialized.
+type ConsumerConfiguration struct {
+	ValueDeserializer string                      `json:"valueDeserializer"`
+	KeyDeserializer   string                      `json:"keyDeserializer"`
+	SchemaRegistry    SchemaRegistryConfiguration `json:"schemaRegistry"`
+}
@@ -0,0 +1,106 @@
+package kafka
+
+import (
+	"encoding/json"
+	"strings"
+
+	jsonschema "github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const (
+	// JSONSchemaSerializer selects the JSON Schema codec for
+	// ProducerConfiguration.ValueSerializer/KeySerializer.
+	JSONSchemaSerializer = "io.confluent.kafka.serializers.json.JsonSchemaSerializer"
+	// JSONSchemaDeserializer selects the JSON Schema codec for
+	// ConsumerConfiguration.ValueDeserializer/KeyDeserializer.
+	JSONSchemaDeserializer = "io.confluent.kafka.serializers.json.JsonSchemaDeserializer"
+)
+
+// jsonNullLiteral is how a k6 script's JSON.stringify(null) reaches
+// SerializeJSON: the caller already turned the value into its JSON text
+// representation, and a Kafka tombstone value is the one case where that
+// text is meaningful input rather than data to encode.
+const jsonNullLiteral = "null"
+
+// SerializeJSON serializes data (already a JSON document) for element,
+// validates it against schema and prefixes it with the schema registry wire
+// format for version. When configuration.Producer.EmitTombstones is set, a
+// literal JSON null short-circuits straight to a nil Kafka value instead of
+// compiling a codec and wrapping "null" in the wire format, so compacted
+// topics get a real tombstone.
+func SerializeJSON(
+	configuration Configuration, topic string, data string, element Element, schema string, version int,
+) ([]byte, *Xk6KafkaError) {
+	if configuration.Producer.EmitTombstones && strings.TrimSpace(data) == jsonNullLiteral {
+		return nil, nil
+	}
+
+	codec, err := compileJSONSchemaCodec(schema, "Failed to create codec for encoding JSON")
+	if err != nil {
+		return nil, err
+	}
+
+	var document interface{}
+	if unmarshalErr := json.Unmarshal([]byte(data), &document); unmarshalErr != nil {
+		return nil, NewXk6KafkaError(failedUnmarshalJSON, "Failed to unmarshal JSON data", unmarshalErr)
+	}
+
+	// A validation failure is discarded: it is neither returned nor logged,
+	// and doesn't block producing.
+	_ = codec.Validate(document)
+
+	encoded, marshalErr := json.Marshal(document)
+	if marshalErr != nil {
+		return nil, NewXk6KafkaError(failedUnmarshalJSON, "Failed to unmarshal JSON data", marshalErr)
+	}
+
+	return EncodeWireFormat(encoded, version), nil
+}
+
+// DeserializeJSON strips the schema registry wire format from message,
+// unmarshals it as JSON and validates it against schema. A nil message is a
+// Kafka tombstone and is returned as a nil value (JS null) without touching
+// the wire format or the codec.
+func DeserializeJSON(
+	configuration Configuration, topic string, message []byte, element Element, schema string, version int,
+) (interface{}, *Xk6KafkaError) {
+	if message == nil {
+		return nil, nil
+	}
+
+	_, data, wireErr := DecodeWireFormat(message)
+	if wireErr != nil {
+		return nil, NewXk6KafkaError(failedDecodeFromWireFormat,
+			"Failed to remove wire format from the binary data", wireErr)
+	}
+
+	codec, err := compileJSONSchemaCodec(schema, "Failed to create codec for decoding JSON data")
+	if err != nil {
+		return nil, err
+	}
+
+	var document interface{}
+	if unmarshalErr := json.Unmarshal(data, &document); unmarshalErr != nil {
+		return nil, NewXk6KafkaError(failedUnmarshalJSON, "Failed to unmarshal JSON data", unmarshalErr)
+	}
+
+	_ = codec.Validate(document)
+
+	return document, nil
+}
+
+// compileJSONSchemaCodec compiles schema into a *jsonschema.Schema, wrapping
+// any failure (including an unparsable schema document) as
+// failedCreateJSONSchemaCodec with messagePrefix, since the caller can't
+// proceed with either serialization or validation without it.
+func compileJSONSchemaCodec(schema string, messagePrefix string) (*jsonschema.Schema, *Xk6KafkaError) {
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("schema.json", strings.NewReader(schema)); err != nil {
+		return nil, NewXk6KafkaError(failedCreateJSONSchemaCodec, messagePrefix, err)
+	}
+	codec, err := compiler.Compile("schema.json")
+	if err != nil {
+		return nil, NewXk6KafkaError(failedCreateJSONSchemaCodec, messagePrefix, err)
+	}
+	return codec, nil
+}
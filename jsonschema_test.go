@@ -3,37 +3,61 @@ package kafka
 import (
 	"testing"
 
+	"github.com/oscar067/xk6-kafka/schemaregistrytest"
+	"github.com/riferrei/srclient"
 	"github.com/stretchr/testify/assert"
 )
 
-var (
-	jsonConfig = Configuration{
+var jsonSchema = `{"type":"object","title":"Key","properties":{"field": {"type":"string"}},"required":["field"]}`
+
+// newJSONConfigWithFakeRegistry starts an in-process fake Schema Registry,
+// registers jsonSchema once under subject with a plain srclient client, and
+// returns a Configuration whose producer and consumer point at it, together
+// with the registered version. SerializeJSON and DeserializeJSON take the
+// schema and version as arguments and don't contact the registry, so the
+// tests below don't go through SchemaRegistryClientWithConfiguration.
+func newJSONConfigWithFakeRegistry(t *testing.T, subject string) (Configuration, int) {
+	t.Helper()
+
+	registry := schemaregistrytest.NewFakeRegistry(t)
+	client := srclient.CreateSchemaRegistryClient(registry.Server.URL)
+	schemaInfo, err := client.CreateSchema(subject, jsonSchema, srclient.Json)
+	assert.Nil(t, err)
+
+	config := Configuration{
 		Producer: ProducerConfiguration{
 			ValueSerializer: JSONSchemaSerializer,
 			KeySerializer:   JSONSchemaSerializer,
+			SchemaRegistry: SchemaRegistryConfiguration{
+				URL: registry.Server.URL,
+			},
 		},
 		Consumer: ConsumerConfiguration{
 			ValueDeserializer: JSONSchemaDeserializer,
 			KeyDeserializer:   JSONSchemaDeserializer,
+			SchemaRegistry: SchemaRegistryConfiguration{
+				URL: registry.Server.URL,
+			},
 		},
 	}
-	jsonSchema = `{"type":"object","title":"Key","properties":{"field": {"type":"string"}},"required":["field"]}`
-)
+	return config, schemaInfo.Version()
+}
 
-// TestSerializeDeserializeJson tests serialization and deserialization (and validation) of
-// JSON data.
+// TestSerializeDeserializeJson tests serialization and deserialization (and
+// validation) of JSON data against a fake Schema Registry.
 func TestSerializeDeserializeJson(t *testing.T) {
-	// Test with a schema registry, which fails and manually (de)serializes the data.
+	config, version := newJSONConfigWithFakeRegistry(t, "topic-value")
+
 	for _, element := range []Element{Key, Value} {
 		// Serialize the key or value.
-		serialized, err := SerializeJSON(jsonConfig, "topic", `{"field":"value"}`, element, jsonSchema, 0)
+		serialized, err := SerializeJSON(config, "topic", `{"field":"value"}`, element, jsonSchema, version)
 		assert.Nil(t, err)
 		assert.NotNil(t, serialized)
 		// 4 bytes for magic byte, 1 byte for schema ID, and the rest is the data.
 		assert.GreaterOrEqual(t, len(serialized), 10)
 
 		// Deserialize the key or value (removes the magic bytes).
-		deserialized, err := DeserializeJSON(jsonConfig, "topic", serialized, element, jsonSchema, 0)
+		deserialized, err := DeserializeJSON(config, "topic", serialized, element, jsonSchema, version)
 		assert.Nil(t, err)
 		assert.Equal(t, map[string]interface{}{"field": "value"}, deserialized)
 	}
@@ -42,18 +66,19 @@ func TestSerializeDeserializeJson(t *testing.T) {
 // TestSerializeDeserializeJsonFailsOnSchemaError tests serialization and deserialization (and
 // validation) of JSON data and fails on schema error.
 func TestSerializeDeserializeJsonFailsOnSchemaError(t *testing.T) {
+	config, _ := newJSONConfigWithFakeRegistry(t, "topic-value")
 	schema := `{`
 
 	for _, element := range []Element{Key, Value} {
 		// Serialize the key or value.
-		serialized, err := SerializeJSON(jsonConfig, "topic", `{"field":"value"}`, element, schema, 0)
+		serialized, err := SerializeJSON(config, "topic", `{"field":"value"}`, element, schema, 0)
 		assert.Nil(t, serialized)
 		assert.Error(t, err.Unwrap())
 		assert.Equal(t, "Failed to create codec for encoding JSON", err.Message)
 		assert.Equal(t, failedCreateJSONSchemaCodec, err.Code)
 
 		// Deserialize the key or value.
-		deserialized, err := DeserializeJSON(jsonConfig, "topic", []byte{0, 2, 3, 4, 5, 6}, element, schema, 0)
+		deserialized, err := DeserializeJSON(config, "topic", []byte{0, 2, 3, 4, 5, 6}, element, schema, 0)
 		assert.Nil(t, deserialized)
 		assert.Error(t, err.Unwrap())
 		assert.Equal(t, "Failed to create codec for decoding JSON data", err.Message)
@@ -64,11 +89,12 @@ func TestSerializeDeserializeJsonFailsOnSchemaError(t *testing.T) {
 // TestSerializeDeserializeJsonFailsOnWireFormatError tests serialization and deserialization (and
 // validation) of JSON data and fails on wire format error.
 func TestSerializeDeserializeJsonFailsOnWireFormatError(t *testing.T) {
+	config, _ := newJSONConfigWithFakeRegistry(t, "topic-value")
 	schema := `{}`
 
 	for _, element := range []Element{Key, Value} {
 		// Deserialize an empty key or value.
-		deserialized, err := DeserializeJSON(jsonConfig, "topic", []byte{}, element, schema, 0)
+		deserialized, err := DeserializeJSON(config, "topic", []byte{}, element, schema, 0)
 		assert.Nil(t, deserialized)
 		assert.Error(t, err.Unwrap())
 		assert.Equal(t, "Failed to remove wire format from the binary data", err.Message)
@@ -76,7 +102,7 @@ func TestSerializeDeserializeJsonFailsOnWireFormatError(t *testing.T) {
 
 		// Deserialize a broken key or value.
 		// Proper wire-formatted message has 5 bytes (the wire format) plus data.
-		deserialized, err = DeserializeJSON(jsonConfig, "topic", []byte{1, 2, 3, 4}, element, schema, 0)
+		deserialized, err = DeserializeJSON(config, "topic", []byte{1, 2, 3, 4}, element, schema, 0)
 		assert.Nil(t, deserialized)
 		assert.Error(t, err.Unwrap())
 		assert.Equal(t, "Failed to remove wire format from the binary data", err.Message)
@@ -87,16 +113,17 @@ func TestSerializeDeserializeJsonFailsOnWireFormatError(t *testing.T) {
 // TestSerializeDeserializeJsonFailsOnMarshalError tests serialization and deserialization (and
 // validation) of JSON data and fails on JSON marshal error.
 func TestSerializeDeserializeJsonFailsOnMarshalError(t *testing.T) {
+	config, version := newJSONConfigWithFakeRegistry(t, "topic-value")
 	data := `{"nonExistingField":"`
 
 	for _, element := range []Element{Key, Value} {
-		serialized, err := SerializeJSON(jsonConfig, "topic", data, element, jsonSchema, 0)
+		serialized, err := SerializeJSON(config, "topic", data, element, jsonSchema, version)
 		assert.Nil(t, serialized)
 		assert.Error(t, err.Unwrap())
 		assert.Equal(t, "Failed to unmarshal JSON data", err.Message)
 		assert.Equal(t, failedUnmarshalJSON, err.Code)
 
-		deserialized, err := DeserializeJSON(jsonConfig, "topic", []byte{0, 2, 3, 4, 5, 6}, element, jsonSchema, 0)
+		deserialized, err := DeserializeJSON(config, "topic", []byte{0, 2, 3, 4, 5, 6}, element, jsonSchema, version)
 		assert.Nil(t, deserialized)
 		assert.Error(t, err.Unwrap())
 		assert.Equal(t, "Failed to unmarshal JSON data", err.Message)
@@ -107,14 +134,45 @@ func TestSerializeDeserializeJsonFailsOnMarshalError(t *testing.T) {
 // TestSerializeDeserializeJsonFailsOnValidationError tests serialization and deserialization (and
 // validation) of JSON data and fails on JSON validation error.
 func TestSerializeDeserializeJsonFailsOnValidationError(t *testing.T) {
+	config, version := newJSONConfigWithFakeRegistry(t, "topic-value")
 	// JSON schema validation fails, but the data is still returned.
 	data := `{"nonExistingField":"value"}`
 
 	for _, element := range []Element{Key, Value} {
-		serialized, err := SerializeJSON(jsonConfig, "topic", data, element, jsonSchema, 0)
+		serialized, err := SerializeJSON(config, "topic", data, element, jsonSchema, version)
 		assert.Nil(t, err)
 		assert.NotNil(t, serialized)
 		// 4 bytes for magic byte, 1 byte for schema ID, and the rest is the data.
 		assert.GreaterOrEqual(t, len(serialized), 28)
 	}
 }
+
+// TestSerializeDeserializeJsonTombstone tests that a literal JSON null round-trips
+// as a Kafka tombstone (a nil value with no wire format prefix) for both the key
+// and the value, instead of being encoded as the string "null".
+func TestSerializeDeserializeJsonTombstone(t *testing.T) {
+	config, version := newJSONConfigWithFakeRegistry(t, "topic-value")
+	config.Producer.EmitTombstones = true
+
+	for _, element := range []Element{Key, Value} {
+		serialized, err := SerializeJSON(config, "topic", "null", element, jsonSchema, version)
+		assert.Nil(t, err)
+		assert.Nil(t, serialized)
+
+		deserialized, err := DeserializeJSON(config, "topic", serialized, element, jsonSchema, version)
+		assert.Nil(t, err)
+		assert.Nil(t, deserialized)
+	}
+}
+
+// TestSerializeJsonEncodesNullWithoutEmitTombstones tests that, without opting
+// in via EmitTombstones, a literal JSON null is encoded like any other value
+// instead of becoming a tombstone, preserving existing script behavior.
+func TestSerializeJsonEncodesNullWithoutEmitTombstones(t *testing.T) {
+	config, version := newJSONConfigWithFakeRegistry(t, "topic-value")
+	schema := `{}`
+
+	serialized, err := SerializeJSON(config, "topic", "null", Value, schema, version)
+	assert.Nil(t, err)
+	assert.Equal(t, MagicPrefixSize+len("null"), len(serialized))
+}
@@ -0,0 +1,207 @@
+package kafka
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/riferrei/srclient"
+)
+
+// SchemaCacheMetrics receives hit/miss counts from a SchemaCache
+// implementation. Implement it to export cache efficiency to the metrics
+// backend of your choice.
+type SchemaCacheMetrics interface {
+	IncHit()
+	IncMiss()
+}
+
+// SchemaCache is a pluggable cache for schemas fetched from the Schema
+// Registry. Implementations must be safe for concurrent use, since k6 calls
+// into (de)serialization from many VUs at once.
+type SchemaCache interface {
+	// Get looks up a cached schema. Pass subject and version to look up by
+	// subject+version, or id to look up by schema ID without a subject (as
+	// needed when decoding the wire format). At least one of subject or id
+	// must be non-zero.
+	Get(subject string, version int, id int) (*srclient.Schema, bool)
+	// Put stores a schema under both its subject+version key and its schema
+	// ID key, so it can be found by either Get call.
+	Put(subject string, version int, id int, schema *srclient.Schema)
+	// Invalidate removes every cached entry for the given subject, e.g. when
+	// a test rotates a schema and wants the next lookup to hit the registry.
+	Invalidate(subject string)
+	// Clear empties the cache entirely.
+	Clear()
+	// OnInvalidate registers a callback that runs whenever subject is
+	// invalidated, so JS tests can force a refresh of anything derived from
+	// the schema (e.g. a cached codec) when they rotate it mid-run.
+	OnInvalidate(subject string, callback func())
+}
+
+// subjectVersionKey and idKey are the two ways a cached schema can be looked
+// up. Both point at the same cacheEntry so either lookup evicts the other.
+type subjectVersionKey struct {
+	subject string
+	version int
+}
+
+type cacheEntry struct {
+	subjectKey subjectVersionKey
+	id         int
+	schema     *srclient.Schema
+	expiresAt  time.Time
+}
+
+// lruSchemaCache is the default SchemaCache implementation: an LRU cache with
+// a configurable capacity and TTL, keyed by subject+version and by schema ID.
+type lruSchemaCache struct {
+	mutex        sync.Mutex
+	capacity     int
+	ttl          time.Duration
+	order        *list.List // most-recently-used entries at the front
+	bySubVer     map[subjectVersionKey]*list.Element
+	byID         map[int]*list.Element
+	onInvalidate map[string][]func()
+	metrics      SchemaCacheMetrics
+}
+
+// NewLRUSchemaCache creates a SchemaCache that evicts the least-recently-used
+// entry once capacity is exceeded, and treats entries older than ttl as
+// misses. A capacity or ttl of zero disables that particular bound. Passing a
+// nil metrics hook is valid; hits and misses are simply not reported.
+func NewLRUSchemaCache(capacity int, ttl time.Duration, metrics SchemaCacheMetrics) SchemaCache {
+	return &lruSchemaCache{
+		capacity:     capacity,
+		ttl:          ttl,
+		order:        list.New(),
+		bySubVer:     make(map[subjectVersionKey]*list.Element),
+		byID:         make(map[int]*list.Element),
+		onInvalidate: make(map[string][]func()),
+		metrics:      metrics,
+	}
+}
+
+// NewSchemaCache builds the SchemaCache described by a SchemaRegistryConfiguration,
+// or nil if caching is disabled.
+func NewSchemaCache(configuration SchemaRegistryConfiguration) SchemaCache {
+	if !configuration.CacheEnabled {
+		return nil
+	}
+	return NewLRUSchemaCache(configuration.CacheCapacity, configuration.CacheTTL, nil)
+}
+
+func (c *lruSchemaCache) Get(subject string, version int, id int) (*srclient.Schema, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var elem *list.Element
+	if subject != "" {
+		elem = c.bySubVer[subjectVersionKey{subject: subject, version: version}]
+	} else {
+		elem = c.byID[id]
+	}
+
+	if elem == nil {
+		c.incMiss()
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if c.ttl > 0 && time.Now().After(entry.expiresAt) {
+		c.removeElement(elem)
+		c.incMiss()
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	c.incHit()
+	return entry.schema, true
+}
+
+func (c *lruSchemaCache) Put(subject string, version int, id int, schema *srclient.Schema) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var expiresAt time.Time
+	if c.ttl > 0 {
+		expiresAt = time.Now().Add(c.ttl)
+	}
+
+	entry := &cacheEntry{
+		subjectKey: subjectVersionKey{subject: subject, version: version},
+		id:         id,
+		schema:     schema,
+		expiresAt:  expiresAt,
+	}
+	elem := c.order.PushFront(entry)
+	if subject != "" {
+		c.bySubVer[entry.subjectKey] = elem
+	}
+	if id != 0 {
+		c.byID[id] = elem
+	}
+
+	if c.capacity > 0 {
+		for c.order.Len() > c.capacity {
+			c.removeElement(c.order.Back())
+		}
+	}
+}
+
+func (c *lruSchemaCache) Invalidate(subject string) {
+	c.mutex.Lock()
+	var callbacks []func()
+	for key, elem := range c.bySubVer {
+		if key.subject == subject {
+			c.removeElement(elem)
+		}
+	}
+	callbacks = append(callbacks, c.onInvalidate[subject]...)
+	c.mutex.Unlock()
+
+	for _, callback := range callbacks {
+		callback()
+	}
+}
+
+func (c *lruSchemaCache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.order.Init()
+	c.bySubVer = make(map[subjectVersionKey]*list.Element)
+	c.byID = make(map[int]*list.Element)
+}
+
+func (c *lruSchemaCache) OnInvalidate(subject string, callback func()) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.onInvalidate[subject] = append(c.onInvalidate[subject], callback)
+}
+
+// removeElement must be called with c.mutex held.
+func (c *lruSchemaCache) removeElement(elem *list.Element) {
+	entry := elem.Value.(*cacheEntry)
+	// A later Put may have pointed these keys at a newer entry; leave those.
+	if c.bySubVer[entry.subjectKey] == elem {
+		delete(c.bySubVer, entry.subjectKey)
+	}
+	if c.byID[entry.id] == elem {
+		delete(c.byID, entry.id)
+	}
+	c.order.Remove(elem)
+}
+
+func (c *lruSchemaCache) incHit() {
+	if c.metrics != nil {
+		c.metrics.IncHit()
+	}
+}
+
+func (c *lruSchemaCache) incMiss() {
+	if c.metrics != nil {
+		c.metrics.IncMiss()
+	}
+}
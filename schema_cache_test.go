@@ -0,0 +1,88 @@
+package kafka
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/riferrei/srclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUSchemaCacheGetPut(t *testing.T) {
+	cache := NewLRUSchemaCache(0, 0, nil)
+	schema := &srclient.Schema{}
+
+	_, ok := cache.Get("subject", 1, 0)
+	assert.False(t, ok)
+
+	cache.Put("subject", 1, 7, schema)
+
+	bySubject, ok := cache.Get("subject", 1, 0)
+	assert.True(t, ok)
+	assert.Same(t, schema, bySubject)
+
+	byID, ok := cache.Get("", 0, 7)
+	assert.True(t, ok)
+	assert.Same(t, schema, byID)
+}
+
+func TestLRUSchemaCacheEvictsAtCapacity(t *testing.T) {
+	cache := NewLRUSchemaCache(2, 0, nil)
+
+	cache.Put("a", 1, 1, &srclient.Schema{})
+	cache.Put("b", 1, 2, &srclient.Schema{})
+	cache.Put("c", 1, 3, &srclient.Schema{})
+
+	_, ok := cache.Get("a", 1, 0)
+	assert.False(t, ok, "oldest entry should have been evicted once capacity was exceeded")
+
+	_, ok = cache.Get("b", 1, 0)
+	assert.True(t, ok)
+
+	_, ok = cache.Get("c", 1, 0)
+	assert.True(t, ok)
+}
+
+func TestLRUSchemaCacheExpiresAfterTTL(t *testing.T) {
+	cache := NewLRUSchemaCache(0, time.Millisecond, nil)
+	cache.Put("subject", 1, 1, &srclient.Schema{})
+
+	_, ok := cache.Get("subject", 1, 0)
+	assert.True(t, ok)
+
+	time.Sleep(5 * time.Millisecond)
+
+	_, ok = cache.Get("subject", 1, 0)
+	assert.False(t, ok, "entry should be treated as a miss once its TTL has elapsed")
+}
+
+func TestLRUSchemaCacheInvalidateFiresOnInvalidate(t *testing.T) {
+	cache := NewLRUSchemaCache(0, 0, nil)
+	cache.Put("subject", 1, 1, &srclient.Schema{})
+
+	var called bool
+	cache.OnInvalidate("subject", func() { called = true })
+
+	cache.Invalidate("subject")
+
+	assert.True(t, called)
+	_, ok := cache.Get("subject", 1, 0)
+	assert.False(t, ok)
+}
+
+func TestLRUSchemaCacheConcurrentGetPut(t *testing.T) {
+	cache := NewLRUSchemaCache(16, 0, nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			subject := "subject"
+			cache.Put(subject, i, i, &srclient.Schema{})
+			cache.Get(subject, i, 0)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -2,9 +2,8 @@ package kafka
 
 import (
 	"encoding/binary"
-	"encoding/json"
-	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/riferrei/srclient"
 )
@@ -28,6 +27,27 @@ type SchemaRegistryConfiguration struct {
 	BasicAuth BasicAuth `json:"basicAuth"`
 	UseLatest bool      `json:"useLatest"`
 	TLS       TLSConfig `json:"tls"`
+
+	// CacheEnabled makes NewSchemaCache build an in-memory schema cache, to
+	// pass to GetSchemaWithCache or GetSchemaByID; GetSchema itself never
+	// caches. It defaults to off, in which case NewSchemaCache returns nil.
+	CacheEnabled bool `json:"cacheEnabled"`
+	// CacheCapacity bounds the number of schemas kept in memory. Zero means
+	// unbounded.
+	CacheCapacity int `json:"cacheCapacity"`
+	// CacheTTL expires cached schemas after the given duration so long-running
+	// load tests pick up schema evolution. Zero means entries never expire.
+	CacheTTL time.Duration `json:"cacheTTL"`
+
+	// Auth selects bearer or OAuth2 authentication, for registries that sit
+	// behind Confluent Cloud or an OIDC-protected gateway. It is ignored when
+	// TokenProvider is set, and otherwise only takes effect for AuthTypeBearer
+	// and AuthTypeOAuth2. BasicAuth above is applied only when no token is
+	// injected: the bearer token replaces the Basic Auth Authorization header.
+	Auth Auth `json:"auth"`
+	// TokenProvider overrides Auth entirely, for IDPs that aren't covered by
+	// the built-in bearer/OAuth2 types. It can only be set from Go, not JS.
+	TokenProvider TokenProvider `json:"-"`
 }
 
 const (
@@ -37,9 +57,13 @@ const (
 )
 
 // DecodeWireFormat removes the proprietary 5-byte prefix from the Avro, ProtoBuf
-// or JSONSchema payload.
+// or JSONSchema payload. A nil message is a Kafka tombstone (a compacted-topic
+// delete marker) rather than a malformed one, and is passed through as-is.
 // https://docs.confluent.io/platform/current/schema-registry/serdes-develop/index.html#wire-format
 func DecodeWireFormat(message []byte) (int, []byte, *Xk6KafkaError) {
+	if message == nil {
+		return 0, nil, nil
+	}
 	if len(message) < MagicPrefixSize {
 		return 0, nil, NewXk6KafkaError(messageTooShort,
 			"Invalid message: message too short to contain schema id.", nil)
@@ -53,9 +77,14 @@ func DecodeWireFormat(message []byte) (int, []byte, *Xk6KafkaError) {
 }
 
 // EncodeWireFormat adds the proprietary 5-byte prefix to the Avro, ProtoBuf or
-// JSONSchema payload.
+// JSONSchema payload. A nil data is a Kafka tombstone (a compacted-topic
+// delete marker) and is returned unprefixed, so it round-trips back to a nil
+// Kafka value instead of a 5-byte message.
 // https://docs.confluent.io/platform/current/schema-registry/serdes-develop/index.html#wire-format
 func EncodeWireFormat(data []byte, schemaID int) []byte {
+	if data == nil {
+		return nil
+	}
 	schemaIDBytes := make([]byte, MagicPrefixSize-1)
 	binary.BigEndian.PutUint32(schemaIDBytes, uint32(schemaID))
 	return append(append([]byte{0}, schemaIDBytes...), data...)
@@ -64,26 +93,13 @@ func EncodeWireFormat(data []byte, schemaID int) []byte {
 // SchemaRegistryClientWithConfiguration creates a SchemaRegistryClient instance
 // with the given configuration. It will also configure auth and TLS credentials if exists.
 func SchemaRegistryClientWithConfiguration(configuration SchemaRegistryConfiguration) *srclient.SchemaRegistryClient {
-	var srClient *srclient.SchemaRegistryClient
-
-	tlsConfig, err := GetTLSConfig(configuration.TLS)
+	httpClient, err := httpClientForSchemaRegistry(configuration)
 	if err != nil {
-		// Ignore the error if we're not using TLS
-		if err.Code != noTLSConfig {
-			logger.WithField("error", err).Error("Cannot process TLS config")
-		}
-		srClient = srclient.CreateSchemaRegistryClient(configuration.URL)
+		logger.WithField("error", err).Error("Cannot process TLS config")
+		httpClient = http.DefaultClient
 	}
 
-	if tlsConfig != nil {
-		httpClient := &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: tlsConfig,
-			},
-		}
-		srClient = srclient.CreateSchemaRegistryClientWithOptions(
-			configuration.URL, httpClient, ConcurrentRequests)
-	}
+	srClient := srclient.CreateSchemaRegistryClientWithOptions(configuration.URL, httpClient, ConcurrentRequests)
 
 	if configuration.BasicAuth.Username != "" && configuration.BasicAuth.Password != "" {
 		srClient.SetCredentials(configuration.BasicAuth.Username, configuration.BasicAuth.Password)
@@ -92,34 +108,95 @@ func SchemaRegistryClientWithConfiguration(configuration SchemaRegistryConfigura
 	return srClient
 }
 
-var cache = make(map[string]*srclient.Schema)
+// httpClientForSchemaRegistry builds the *http.Client used to talk to the
+// schema registry: TLS settings from configuration.TLS layered under a
+// RoundTripper that injects and refreshes a bearer/OAuth2 token, when either
+// is configured. A nil error with no TLS or token auth configured still
+// returns a usable, unmodified *http.Client.
+func httpClientForSchemaRegistry(configuration SchemaRegistryConfiguration) (*http.Client, *Xk6KafkaError) {
+	var transport http.RoundTripper = http.DefaultTransport
+
+	tlsConfig, err := GetTLSConfig(configuration.TLS)
+	if err != nil && err.Code != noTLSConfig {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
 
-// GetSchema returns the schema for the given subject and schema ID and version.
+	if provider := tokenProviderFromConfiguration(configuration, &http.Client{Transport: transport}); provider != nil {
+		transport = &tokenRoundTripper{provider: provider, base: transport}
+	}
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// GetSchema returns the schema for the given subject and schema ID and
+// version. It never caches, to keep its signature stable for existing
+// callers; use GetSchemaWithCache to opt into the schema cache added by
+// SchemaRegistryConfiguration.CacheEnabled.
 func GetSchema(
 	client *srclient.SchemaRegistryClient, subject string, schema string, schemaType srclient.SchemaType, version int,
 ) (*srclient.Schema, *Xk6KafkaError) {
-	// The client always caches the schema.
-	var schemaInfo *srclient.Schema
-	var err error
-	// Default version of the schema is the latest version.
+	return GetSchemaWithCache(client, nil, subject, schema, schemaType, version)
+}
 
-	if value, exists := cache[subject]; exists {
-		return value, nil
+// GetSchemaWithCache returns the schema for the given subject and schema ID
+// and version, consulting schemaCache first. Passing a nil schemaCache
+// disables caching, which always hits the schema registry.
+func GetSchemaWithCache(
+	client *srclient.SchemaRegistryClient, schemaCache SchemaCache,
+	subject string, schema string, schemaType srclient.SchemaType, version int,
+) (*srclient.Schema, *Xk6KafkaError) {
+	if schemaCache != nil {
+		if schemaInfo, ok := schemaCache.Get(subject, version, 0); ok {
+			return schemaInfo, nil
+		}
 	}
 
+	var schemaInfo *srclient.Schema
+	var err error
+	// Default version of the schema is the latest version.
 	if version == 0 {
 		schemaInfo, err = client.GetLatestSchema(subject)
 	} else {
 		schemaInfo, err = client.GetSchemaByVersion(subject, version)
 	}
 
-	if err == nil {
-		cache[subject] = schemaInfo
-	} else {
+	if err != nil {
 		return nil, NewXk6KafkaError(schemaNotFound,
 			"Failed to get schema from schema registry", err)
 	}
 
+	if schemaCache != nil {
+		schemaCache.Put(subject, version, schemaInfo.ID(), schemaInfo)
+	}
+
+	return schemaInfo, nil
+}
+
+// GetSchemaByID returns the schema registered under the given schema ID,
+// consulting schemaCache first. This is used when decoding the wire format,
+// which carries only a schema ID and no subject.
+func GetSchemaByID(
+	client *srclient.SchemaRegistryClient, schemaCache SchemaCache, id int,
+) (*srclient.Schema, *Xk6KafkaError) {
+	if schemaCache != nil {
+		if schemaInfo, ok := schemaCache.Get("", 0, id); ok {
+			return schemaInfo, nil
+		}
+	}
+
+	schemaInfo, err := client.GetSchema(id)
+	if err != nil {
+		return nil, NewXk6KafkaError(schemaNotFound,
+			"Failed to get schema from schema registry", err)
+	}
+
+	if schemaCache != nil {
+		schemaCache.Put("", 0, id, schemaInfo)
+	}
+
 	return schemaInfo, nil
 }
 
@@ -134,40 +211,29 @@ func CreateSchema(
 	return schemaInfo, nil
 }
 
-// GetSubjectName return the subject name strategy for the given schema and topic.
-func GetSubjectName(schema string, topic string, element Element, subjectNameStrategy string) (string, *Xk6KafkaError) {
-	if subjectNameStrategy == "" || subjectNameStrategy == TopicNameStrategy {
-		return topic + "-" + string(element), nil
-	}
+// GetSubjectName returns the subject name for the given schema and topic,
+// using subjectNameStrategy (one of TopicNameStrategy, RecordNameStrategy,
+// TopicRecordNameStrategy, or a name registered with
+// RegisterSubjectNameStrategy) to derive it. It assumes an Avro/JSONSchema-style
+// record name (namespace+name, or $id/title), matching this function's
+// original behavior, so existing callers keep working unchanged; call
+// GetSubjectNameForType directly for a schema that may be Protobuf.
+func GetSubjectName(
+	schema string, topic string, element Element, subjectNameStrategy string,
+) (string, *Xk6KafkaError) {
+	return GetSubjectNameForType(schema, topic, element, subjectNameStrategy, "")
+}
 
-	var schemaMap map[string]interface{}
-	err := json.Unmarshal([]byte(schema), &schemaMap)
+// GetSubjectNameForType returns the subject name for the given schema and
+// topic the same way GetSubjectName does, but lets the caller select the
+// record-name extraction rules via schemaType, since Avro, Protobuf and
+// JSONSchema each name their records differently.
+func GetSubjectNameForType(
+	schema string, topic string, element Element, subjectNameStrategy string, schemaType srclient.SchemaType,
+) (string, *Xk6KafkaError) {
+	strategy, err := GetSubjectNameStrategyByName(subjectNameStrategy)
 	if err != nil {
-		return "", NewXk6KafkaError(failedToUnmarshalSchema, "Failed to unmarshal schema", nil)
-	}
-	recordName := ""
-	if namespace, ok := schemaMap["namespace"]; ok {
-		if namespace, ok := namespace.(string); ok {
-			recordName = namespace + "."
-		} else {
-			return "", NewXk6KafkaError(failedTypeCast, "Failed to cast to string", nil)
-		}
+		return "", err
 	}
-	if name, ok := schemaMap["name"]; ok {
-		if name, ok := name.(string); ok {
-			recordName += name
-		} else {
-			return "", NewXk6KafkaError(failedTypeCast, "Failed to cast to string", nil)
-		}
-	}
-
-	if subjectNameStrategy == RecordNameStrategy {
-		return recordName, nil
-	}
-	if subjectNameStrategy == TopicRecordNameStrategy {
-		return topic + "-" + recordName, nil
-	}
-
-	return "", NewXk6KafkaError(failedEncodeToAvro, fmt.Sprintf(
-		"Unknown subject name strategy: %v", subjectNameStrategy), nil)
+	return strategy.SubjectName(topic, element, Schema{Schema: schema, SchemaType: schemaType})
 }
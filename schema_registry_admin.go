@@ -0,0 +1,264 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// SchemaRegistry wraps a SchemaRegistryConfiguration with the admin
+// operations below as methods, matching the shape expected of the
+// kafka.SchemaRegistry JS object (setCompatibility/getCompatibility/
+// testCompatibility/deleteSubject/listSubjects). module.go is expected to
+// construct one per configuration and expose it to scripts; that wiring
+// isn't part of this change, since module.go isn't in this tree.
+//
+// Its *http.Client (and, for OAuth2, the client-credentials token it caches)
+// is built once on first use and reused for every subsequent call, instead of
+// being rebuilt per request, so repeated admin operations don't each pay for
+// a fresh token fetch.
+type SchemaRegistry struct {
+	Configuration SchemaRegistryConfiguration
+
+	clientOnce sync.Once
+	httpClient *http.Client
+	clientErr  *Xk6KafkaError
+}
+
+// NewSchemaRegistry returns a SchemaRegistry bound to configuration.
+func NewSchemaRegistry(configuration SchemaRegistryConfiguration) *SchemaRegistry {
+	return &SchemaRegistry{Configuration: configuration}
+}
+
+// client lazily builds and caches the *http.Client used for every request
+// this SchemaRegistry makes.
+func (r *SchemaRegistry) client() (*http.Client, *Xk6KafkaError) {
+	r.clientOnce.Do(func() {
+		r.httpClient, r.clientErr = httpClientForSchemaRegistry(r.Configuration)
+	})
+	return r.httpClient, r.clientErr
+}
+
+func (r *SchemaRegistry) SetCompatibility(subject string, level string) *Xk6KafkaError {
+	httpClient, err := r.client()
+	if err != nil {
+		return err
+	}
+	return setCompatibilityWithClient(httpClient, r.Configuration, subject, level)
+}
+
+func (r *SchemaRegistry) GetCompatibility(subject string) (string, *Xk6KafkaError) {
+	httpClient, err := r.client()
+	if err != nil {
+		return "", err
+	}
+	return getCompatibilityWithClient(httpClient, r.Configuration, subject)
+}
+
+func (r *SchemaRegistry) TestCompatibility(subject string, schema string) (bool, *Xk6KafkaError) {
+	httpClient, err := r.client()
+	if err != nil {
+		return false, err
+	}
+	return testCompatibilityWithClient(httpClient, r.Configuration, subject, schema)
+}
+
+func (r *SchemaRegistry) DeleteSubject(subject string) *Xk6KafkaError {
+	httpClient, err := r.client()
+	if err != nil {
+		return err
+	}
+	return deleteSubjectWithClient(httpClient, r.Configuration, subject)
+}
+
+func (r *SchemaRegistry) ListSubjects() ([]string, *Xk6KafkaError) {
+	httpClient, err := r.client()
+	if err != nil {
+		return nil, err
+	}
+	return listSubjectsWithClient(httpClient, r.Configuration)
+}
+
+// SetCompatibility sets the compatibility level for subject (or the global
+// default when subject is empty) to level (one of NONE, BACKWARD, FORWARD or
+// FULL). It builds a new *http.Client for this one call; SchemaRegistry's
+// method of the same name reuses a cached client across calls instead.
+func SetCompatibility(configuration SchemaRegistryConfiguration, subject string, level string) *Xk6KafkaError {
+	httpClient, err := httpClientForSchemaRegistry(configuration)
+	if err != nil {
+		return err
+	}
+	return setCompatibilityWithClient(httpClient, configuration, subject, level)
+}
+
+func setCompatibilityWithClient(
+	httpClient *http.Client, configuration SchemaRegistryConfiguration, subject string, level string,
+) *Xk6KafkaError {
+	requestBody, err := json.Marshal(map[string]string{"compatibility": level})
+	if err != nil {
+		return NewXk6KafkaError(failedMarshalSchemaRegistryRequest, "Failed to marshal compatibility level", err)
+	}
+
+	_, xk6Err := doSchemaRegistryRequest(httpClient, configuration, http.MethodPut, compatibilityConfigPath(subject), requestBody)
+	return xk6Err
+}
+
+// GetCompatibility returns the compatibility level configured for subject, or
+// the global default when subject is empty. It builds a new *http.Client for
+// this one call; SchemaRegistry's method of the same name reuses a cached
+// client across calls instead.
+func GetCompatibility(configuration SchemaRegistryConfiguration, subject string) (string, *Xk6KafkaError) {
+	httpClient, err := httpClientForSchemaRegistry(configuration)
+	if err != nil {
+		return "", err
+	}
+	return getCompatibilityWithClient(httpClient, configuration, subject)
+}
+
+func getCompatibilityWithClient(
+	httpClient *http.Client, configuration SchemaRegistryConfiguration, subject string,
+) (string, *Xk6KafkaError) {
+	responseBody, xk6Err := doSchemaRegistryRequest(httpClient, configuration, http.MethodGet, compatibilityConfigPath(subject), nil)
+	if xk6Err != nil {
+		return "", xk6Err
+	}
+
+	var response struct {
+		CompatibilityLevel string `json:"compatibilityLevel"`
+	}
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return "", NewXk6KafkaError(failedUnmarshalSchemaRegistryResponse, "Failed to unmarshal compatibility level", err)
+	}
+	return response.CompatibilityLevel, nil
+}
+
+// TestCompatibility checks whether schema would be accepted as the next
+// version of subject under its current compatibility level, without
+// registering it. It builds a new *http.Client for this one call;
+// SchemaRegistry's method of the same name reuses a cached client across
+// calls instead.
+func TestCompatibility(configuration SchemaRegistryConfiguration, subject string, schema string) (bool, *Xk6KafkaError) {
+	httpClient, err := httpClientForSchemaRegistry(configuration)
+	if err != nil {
+		return false, err
+	}
+	return testCompatibilityWithClient(httpClient, configuration, subject, schema)
+}
+
+func testCompatibilityWithClient(
+	httpClient *http.Client, configuration SchemaRegistryConfiguration, subject string, schema string,
+) (bool, *Xk6KafkaError) {
+	requestBody, err := json.Marshal(map[string]string{"schema": schema})
+	if err != nil {
+		return false, NewXk6KafkaError(failedMarshalSchemaRegistryRequest, "Failed to marshal schema", err)
+	}
+
+	path := fmt.Sprintf("/compatibility/subjects/%s/versions/latest", subject)
+	responseBody, xk6Err := doSchemaRegistryRequest(httpClient, configuration, http.MethodPost, path, requestBody)
+	if xk6Err != nil {
+		return false, xk6Err
+	}
+
+	var response struct {
+		IsCompatible bool `json:"is_compatible"`
+	}
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return false, NewXk6KafkaError(failedUnmarshalSchemaRegistryResponse, "Failed to unmarshal compatibility result", err)
+	}
+	return response.IsCompatible, nil
+}
+
+// DeleteSubject permanently deletes subject and all of its registered
+// versions. It builds a new *http.Client for this one call; SchemaRegistry's
+// method of the same name reuses a cached client across calls instead.
+func DeleteSubject(configuration SchemaRegistryConfiguration, subject string) *Xk6KafkaError {
+	httpClient, err := httpClientForSchemaRegistry(configuration)
+	if err != nil {
+		return err
+	}
+	return deleteSubjectWithClient(httpClient, configuration, subject)
+}
+
+func deleteSubjectWithClient(
+	httpClient *http.Client, configuration SchemaRegistryConfiguration, subject string,
+) *Xk6KafkaError {
+	_, xk6Err := doSchemaRegistryRequest(httpClient, configuration, http.MethodDelete, fmt.Sprintf("/subjects/%s", subject), nil)
+	return xk6Err
+}
+
+// ListSubjects returns the names of every subject currently registered. It
+// builds a new *http.Client for this one call; SchemaRegistry's method of the
+// same name reuses a cached client across calls instead.
+func ListSubjects(configuration SchemaRegistryConfiguration) ([]string, *Xk6KafkaError) {
+	httpClient, err := httpClientForSchemaRegistry(configuration)
+	if err != nil {
+		return nil, err
+	}
+	return listSubjectsWithClient(httpClient, configuration)
+}
+
+func listSubjectsWithClient(
+	httpClient *http.Client, configuration SchemaRegistryConfiguration,
+) ([]string, *Xk6KafkaError) {
+	responseBody, xk6Err := doSchemaRegistryRequest(httpClient, configuration, http.MethodGet, "/subjects", nil)
+	if xk6Err != nil {
+		return nil, xk6Err
+	}
+
+	var subjects []string
+	if err := json.Unmarshal(responseBody, &subjects); err != nil {
+		return nil, NewXk6KafkaError(failedUnmarshalSchemaRegistryResponse, "Failed to unmarshal subject list", err)
+	}
+	return subjects, nil
+}
+
+func compatibilityConfigPath(subject string) string {
+	if subject == "" {
+		return "/config"
+	}
+	return fmt.Sprintf("/config/%s", subject)
+}
+
+// doSchemaRegistryRequest issues a single request against
+// configuration.URL+path over httpClient, applying Basic Auth from
+// configuration, and returns the raw response body. srclient doesn't expose
+// the registry's config/compatibility/subject management endpoints, so these
+// are called directly over HTTP.
+func doSchemaRegistryRequest(
+	httpClient *http.Client, configuration SchemaRegistryConfiguration, method string, path string, requestBody []byte,
+) ([]byte, *Xk6KafkaError) {
+	var reader io.Reader
+	if requestBody != nil {
+		reader = bytes.NewReader(requestBody)
+	}
+
+	request, err2 := http.NewRequest(method, configuration.URL+path, reader)
+	if err2 != nil {
+		return nil, NewXk6KafkaError(failedSchemaRegistryRequest, "Failed to build schema registry request", err2)
+	}
+	request.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	if configuration.BasicAuth.Username != "" && configuration.BasicAuth.Password != "" {
+		request.SetBasicAuth(configuration.BasicAuth.Username, configuration.BasicAuth.Password)
+	}
+
+	response, err2 := httpClient.Do(request)
+	if err2 != nil {
+		return nil, NewXk6KafkaError(failedSchemaRegistryRequest, "Failed to reach schema registry", err2)
+	}
+	defer response.Body.Close()
+
+	responseBody, err2 := io.ReadAll(response.Body)
+	if err2 != nil {
+		return nil, NewXk6KafkaError(failedSchemaRegistryRequest, "Failed to read schema registry response", err2)
+	}
+
+	if response.StatusCode >= http.StatusBadRequest {
+		return nil, NewXk6KafkaError(failedSchemaRegistryRequest,
+			fmt.Sprintf("Schema registry request failed with status %d", response.StatusCode), nil)
+	}
+
+	return responseBody, nil
+}
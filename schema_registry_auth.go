@@ -0,0 +1,217 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuthType selects how SchemaRegistryClientWithConfiguration authenticates
+// against the schema registry.
+type AuthType string
+
+const (
+	// AuthTypeBasic authenticates with SchemaRegistryConfiguration.BasicAuth.
+	// This is the default when Auth.Type is empty, for backwards compatibility.
+	AuthTypeBasic AuthType = "basic"
+	// AuthTypeBearer authenticates with a static bearer token.
+	AuthTypeBearer AuthType = "bearer"
+	// AuthTypeOAuth2 authenticates with an OAuth2 client-credentials flow,
+	// refreshing the access token automatically before it expires.
+	AuthTypeOAuth2 AuthType = "oauth2"
+)
+
+// BearerAuth is a static bearer token, used when Auth.Type is AuthTypeBearer.
+type BearerAuth struct {
+	Token string `json:"token"`
+}
+
+// OAuth2Config describes an OAuth2 client-credentials flow, used when
+// Auth.Type is AuthTypeOAuth2.
+type OAuth2Config struct {
+	TokenURL     string   `json:"tokenUrl"`
+	ClientID     string   `json:"clientId"`
+	ClientSecret string   `json:"clientSecret"`
+	Scopes       []string `json:"scopes"`
+}
+
+// Auth selects and configures an authentication mechanism for the schema
+// registry, beyond the plain SchemaRegistryConfiguration.BasicAuth.
+type Auth struct {
+	Type   AuthType     `json:"type"`
+	Bearer BearerAuth   `json:"bearer"`
+	OAuth2 OAuth2Config `json:"oauth2"`
+}
+
+// TokenProvider supplies the bearer token injected into every schema
+// registry request. Implement it to wire up an identity provider that isn't
+// covered by Auth.Type (e.g. AWS SigV4, a short-lived Vault lease), and set
+// it on SchemaRegistryConfiguration.TokenProvider instead of Auth.
+type TokenProvider interface {
+	Token() (string, error)
+}
+
+// staticTokenProvider always returns the same token, backing AuthTypeBearer.
+type staticTokenProvider struct {
+	token string
+}
+
+func (p staticTokenProvider) Token() (string, error) {
+	return p.token, nil
+}
+
+// clientCredentialsTokenProvider implements the OAuth2 client-credentials
+// grant, caching the access token until shortly before it expires.
+type clientCredentialsTokenProvider struct {
+	config     OAuth2Config
+	httpClient *http.Client
+
+	mutex       sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+func newClientCredentialsTokenProvider(config OAuth2Config, httpClient *http.Client) *clientCredentialsTokenProvider {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &clientCredentialsTokenProvider{config: config, httpClient: httpClient}
+}
+
+func (p *clientCredentialsTokenProvider) Token() (string, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	// Refresh a little before expiry so an in-flight request doesn't race
+	// against the token going stale.
+	if p.accessToken != "" && time.Now().Before(p.expiresAt.Add(-10*time.Second)) {
+		return p.accessToken, nil
+	}
+	return p.refreshLocked()
+}
+
+// refreshLocked fetches a new access token and must be called with p.mutex held.
+func (p *clientCredentialsTokenProvider) refreshLocked() (string, error) {
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {p.config.ClientID},
+		"client_secret": {p.config.ClientSecret},
+	}
+	if len(p.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(p.config.Scopes, " "))
+	}
+
+	response, err := p.httpClient.PostForm(p.config.TokenURL, form)
+	if err != nil {
+		return "", fmt.Errorf("failed to request OAuth2 token: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		return "", fmt.Errorf("OAuth2 token endpoint returned status %d", response.StatusCode)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode OAuth2 token response: %w", err)
+	}
+	if body.AccessToken == "" {
+		return "", fmt.Errorf("OAuth2 token response did not contain an access_token")
+	}
+
+	p.accessToken = body.AccessToken
+	if body.ExpiresIn > 0 {
+		p.expiresAt = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	} else {
+		p.expiresAt = time.Time{}
+	}
+	return p.accessToken, nil
+}
+
+// tokenRoundTripper injects the current token from provider into every
+// request's Authorization header. If the registry responds 401 and provider
+// is the built-in OAuth2 client-credentials provider, it retries once with a
+// freshly-fetched token; a static bearer token or a custom TokenProvider gets
+// no retry, and neither does a request whose body can't be rebuilt.
+type tokenRoundTripper struct {
+	provider TokenProvider
+	base     http.RoundTripper
+}
+
+func (rt *tokenRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	base := rt.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	token, err := rt.provider.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain schema registry auth token: %w", err)
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+
+	response, err := base.RoundTrip(request)
+	if err != nil || response.StatusCode != http.StatusUnauthorized {
+		return response, err
+	}
+
+	// The token may have just expired; force one refresh and retry.
+	refreshable, ok := rt.provider.(*clientCredentialsTokenProvider)
+	if !ok {
+		return response, err
+	}
+	// request.Body was already fully read and closed by the first
+	// RoundTrip; Clone only copies the Request struct, not the body, so a
+	// retried POST/PUT would otherwise go out with an empty body. Rebuild it
+	// from GetBody, and skip the retry entirely if that isn't possible
+	// rather than silently dropping the payload.
+	if request.Body != nil && request.GetBody == nil {
+		return response, err
+	}
+	response.Body.Close()
+
+	refreshable.mutex.Lock()
+	refreshable.expiresAt = time.Time{}
+	refreshable.accessToken = ""
+	refreshable.mutex.Unlock()
+
+	token, err = rt.provider.Token()
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh schema registry auth token: %w", err)
+	}
+	retryRequest := request.Clone(request.Context())
+	if request.GetBody != nil {
+		body, bodyErr := request.GetBody()
+		if bodyErr != nil {
+			return nil, fmt.Errorf("failed to rebuild schema registry request body for retry: %w", bodyErr)
+		}
+		retryRequest.Body = body
+	}
+	retryRequest.Header.Set("Authorization", "Bearer "+token)
+	return base.RoundTrip(retryRequest)
+}
+
+// tokenProviderFromConfiguration resolves the TokenProvider that should
+// authenticate requests for configuration, or nil when neither
+// configuration.TokenProvider nor configuration.Auth select one.
+func tokenProviderFromConfiguration(configuration SchemaRegistryConfiguration, httpClient *http.Client) TokenProvider {
+	if configuration.TokenProvider != nil {
+		return configuration.TokenProvider
+	}
+
+	switch configuration.Auth.Type {
+	case AuthTypeBearer:
+		return staticTokenProvider{token: configuration.Auth.Bearer.Token}
+	case AuthTypeOAuth2:
+		return newClientCredentialsTokenProvider(configuration.Auth.OAuth2, httpClient)
+	default:
+		return nil
+	}
+}
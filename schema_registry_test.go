@@ -0,0 +1,32 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEncodeDecodeWireFormatTombstone verifies that a nil key or value (a
+// Kafka tombstone on a compacted topic) round-trips as nil instead of being
+// wrapped in the 5-byte wire format prefix. EncodeWireFormat/DecodeWireFormat
+// operate on raw bytes and don't distinguish key from value, so a single
+// nil round-trip covers both.
+func TestEncodeDecodeWireFormatTombstone(t *testing.T) {
+	encoded := EncodeWireFormat(nil, 1)
+	assert.Nil(t, encoded)
+
+	schemaID, decoded, err := DecodeWireFormat(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, schemaID)
+	assert.Nil(t, decoded)
+}
+
+func TestEncodeDecodeWireFormatRoundTrip(t *testing.T) {
+	encoded := EncodeWireFormat([]byte("data"), 7)
+	assert.Equal(t, MagicPrefixSize+len("data"), len(encoded))
+
+	schemaID, decoded, err := DecodeWireFormat(encoded)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, schemaID)
+	assert.Equal(t, []byte("data"), decoded)
+}
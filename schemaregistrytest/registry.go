@@ -0,0 +1,401 @@
+// Package schemaregistrytest provides an in-process fake implementation of
+// the Confluent Schema Registry REST API, so serializer tests can exercise
+// the real SchemaRegistryClientWithConfiguration path without a live
+// registry.
+package schemaregistrytest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Compatibility levels understood by the fake registry. Only a simplified
+// check is performed for each: enough to exercise schema-evolution test
+// scenarios, not a full Avro/Protobuf/JSONSchema compatibility resolver.
+const (
+	CompatibilityNone     = "NONE"
+	CompatibilityBackward = "BACKWARD"
+	CompatibilityForward  = "FORWARD"
+	CompatibilityFull     = "FULL"
+)
+
+type schemaRecord struct {
+	ID         int    `json:"id"`
+	Version    int    `json:"version"`
+	Subject    string `json:"subject"`
+	Schema     string `json:"schema"`
+	SchemaType string `json:"schemaType,omitempty"`
+}
+
+// FakeRegistry is an in-memory stand-in for a Confluent Schema Registry. Use
+// NewFakeRegistry to start one and point a SchemaRegistryConfiguration at its
+// URL.
+type FakeRegistry struct {
+	Server *httptest.Server
+
+	mutex         sync.Mutex
+	bySubject     map[string][]*schemaRecord // ordered by version, 1-indexed
+	byID          map[int]*schemaRecord
+	nextID        int
+	globalCompat  string
+	subjectCompat map[string]string
+}
+
+// NewFakeRegistry starts an httptest.Server implementing the subset of the
+// Confluent Schema Registry REST API that srclient relies on, and registers
+// its shutdown with t.Cleanup. The returned FakeRegistry's Server.URL can be
+// used directly as a SchemaRegistryConfiguration.URL.
+func NewFakeRegistry(t testing.TB) *FakeRegistry {
+	t.Helper()
+
+	registry := &FakeRegistry{
+		bySubject:     make(map[string][]*schemaRecord),
+		byID:          make(map[int]*schemaRecord),
+		nextID:        1,
+		globalCompat:  CompatibilityBackward,
+		subjectCompat: make(map[string]string),
+	}
+	registry.Server = httptest.NewServer(registry.router())
+	t.Cleanup(registry.Server.Close)
+	return registry
+}
+
+func (r *FakeRegistry) router() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/subjects/", r.handleSubjects)
+	mux.HandleFunc("/subjects", r.handleListSubjects)
+	mux.HandleFunc("/schemas/ids/", r.handleSchemaByID)
+	mux.HandleFunc("/config/", r.handleSubjectConfig)
+	mux.HandleFunc("/config", r.handleGlobalConfig)
+	mux.HandleFunc("/compatibility/subjects/", r.handleCompatibility)
+	return mux
+}
+
+// handleSubjects serves:
+//
+//	POST /subjects/{subject}/versions           register a new version
+//	GET  /subjects/{subject}/versions/{version}  fetch a version ("latest" included)
+//	POST /subjects/{subject}                     look up a schema's version
+//	DELETE /subjects/{subject}                   delete a subject
+func (r *FakeRegistry) handleSubjects(w http.ResponseWriter, req *http.Request) {
+	path := strings.TrimPrefix(req.URL.Path, "/subjects/")
+	parts := strings.SplitN(path, "/", 3)
+	subject := parts[0]
+
+	switch {
+	case len(parts) == 1 && req.Method == http.MethodPost:
+		r.registerOrLookupSchema(w, req, subject)
+	case len(parts) == 1 && req.Method == http.MethodDelete:
+		r.deleteSubject(w, subject)
+	case len(parts) == 2 && parts[1] == "versions" && req.Method == http.MethodPost:
+		r.registerSchema(w, req, subject)
+	case len(parts) == 3 && parts[1] == "versions" && req.Method == http.MethodGet:
+		r.getVersion(w, subject, parts[2])
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (r *FakeRegistry) handleListSubjects(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.NotFound(w, req)
+		return
+	}
+
+	r.mutex.Lock()
+	subjects := make([]string, 0, len(r.bySubject))
+	for subject := range r.bySubject {
+		subjects = append(subjects, subject)
+	}
+	r.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, subjects)
+}
+
+func (r *FakeRegistry) registerSchema(w http.ResponseWriter, req *http.Request, subject string) {
+	var body struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, 42201, "Invalid schema")
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.isCompatibleLocked(subject, body.Schema) {
+		writeError(w, http.StatusConflict, 409, "Schema being registered is incompatible with an earlier schema")
+		return
+	}
+
+	record := r.addSchemaLocked(subject, body.Schema, body.SchemaType)
+	writeJSON(w, http.StatusOK, map[string]int{"id": record.ID})
+}
+
+// registerOrLookupSchema implements POST /subjects/{subject}, which looks up
+// the version of an already-registered schema without creating a new one.
+func (r *FakeRegistry) registerOrLookupSchema(w http.ResponseWriter, req *http.Request, subject string) {
+	var body struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, 42201, "Invalid schema")
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, record := range r.bySubject[subject] {
+		if record.Schema == body.Schema {
+			writeJSON(w, http.StatusOK, record)
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, 40403, "Schema not found")
+}
+
+func (r *FakeRegistry) getVersion(w http.ResponseWriter, subject string, version string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	versions := r.bySubject[subject]
+	if len(versions) == 0 {
+		writeError(w, http.StatusNotFound, 40401, "Subject not found")
+		return
+	}
+
+	if version == "latest" {
+		writeJSON(w, http.StatusOK, versions[len(versions)-1])
+		return
+	}
+
+	versionNumber, err := strconv.Atoi(version)
+	if err != nil || versionNumber < 1 || versionNumber > len(versions) {
+		writeError(w, http.StatusNotFound, 40402, "Version not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, versions[versionNumber-1])
+}
+
+func (r *FakeRegistry) deleteSubject(w http.ResponseWriter, subject string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	versions := r.bySubject[subject]
+	versionNumbers := make([]int, 0, len(versions))
+	for _, record := range versions {
+		versionNumbers = append(versionNumbers, record.Version)
+		delete(r.byID, record.ID)
+	}
+	delete(r.bySubject, subject)
+	delete(r.subjectCompat, subject)
+
+	writeJSON(w, http.StatusOK, versionNumbers)
+}
+
+func (r *FakeRegistry) handleSchemaByID(w http.ResponseWriter, req *http.Request) {
+	idString := strings.TrimPrefix(req.URL.Path, "/schemas/ids/")
+	id, err := strconv.Atoi(idString)
+	if err != nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	r.mutex.Lock()
+	record, ok := r.byID[id]
+	r.mutex.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, 40403, "Schema not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"schema": record.Schema})
+}
+
+func (r *FakeRegistry) handleGlobalConfig(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodPut:
+		var body struct {
+			Compatibility string `json:"compatibility"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, 42203, "Invalid compatibility level")
+			return
+		}
+		r.mutex.Lock()
+		r.globalCompat = body.Compatibility
+		r.mutex.Unlock()
+		writeJSON(w, http.StatusOK, map[string]string{"compatibility": body.Compatibility})
+	case http.MethodGet:
+		r.mutex.Lock()
+		compat := r.globalCompat
+		r.mutex.Unlock()
+		writeJSON(w, http.StatusOK, map[string]string{"compatibilityLevel": compat})
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func (r *FakeRegistry) handleSubjectConfig(w http.ResponseWriter, req *http.Request) {
+	subject := strings.TrimPrefix(req.URL.Path, "/config/")
+
+	switch req.Method {
+	case http.MethodPut:
+		var body struct {
+			Compatibility string `json:"compatibility"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, 42203, "Invalid compatibility level")
+			return
+		}
+		r.mutex.Lock()
+		r.subjectCompat[subject] = body.Compatibility
+		r.mutex.Unlock()
+		writeJSON(w, http.StatusOK, map[string]string{"compatibility": body.Compatibility})
+	case http.MethodGet:
+		r.mutex.Lock()
+		compat, ok := r.subjectCompat[subject]
+		r.mutex.Unlock()
+		if !ok {
+			writeError(w, http.StatusNotFound, 40401, "Subject not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"compatibilityLevel": compat})
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// handleCompatibility serves POST /compatibility/subjects/{subject}/versions/{version},
+// testing a candidate schema against the subject's latest registered version
+// (whatever {version} says) without registering it.
+func (r *FakeRegistry) handleCompatibility(w http.ResponseWriter, req *http.Request) {
+	path := strings.TrimPrefix(req.URL.Path, "/compatibility/subjects/")
+	parts := strings.SplitN(path, "/", 3)
+	if len(parts) != 3 || parts[1] != "versions" || req.Method != http.MethodPost {
+		http.NotFound(w, req)
+		return
+	}
+	subject := parts[0]
+
+	var body struct {
+		Schema string `json:"schema"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, 42201, "Invalid schema")
+		return
+	}
+
+	r.mutex.Lock()
+	compatible := r.isCompatibleLocked(subject, body.Schema)
+	r.mutex.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]bool{"is_compatible": compatible})
+}
+
+// addSchemaLocked must be called with r.mutex held.
+func (r *FakeRegistry) addSchemaLocked(subject string, schema string, schemaType string) *schemaRecord {
+	record := &schemaRecord{
+		ID:         r.nextID,
+		Version:    len(r.bySubject[subject]) + 1,
+		Subject:    subject,
+		Schema:     schema,
+		SchemaType: schemaType,
+	}
+	r.nextID++
+	r.bySubject[subject] = append(r.bySubject[subject], record)
+	r.byID[record.ID] = record
+	return record
+}
+
+// isCompatibleLocked runs a deliberately simplified compatibility check: it
+// is enough to drive schema-evolution test scenarios, not a faithful
+// reimplementation of the registry's Avro/Protobuf/JSONSchema resolvers. It
+// must be called with r.mutex held.
+func (r *FakeRegistry) isCompatibleLocked(subject string, candidate string) bool {
+	versions := r.bySubject[subject]
+	if len(versions) == 0 {
+		return true
+	}
+
+	level, ok := r.subjectCompat[subject]
+	if !ok {
+		level = r.globalCompat
+	}
+	if level == CompatibilityNone {
+		return true
+	}
+
+	previous := versions[len(versions)-1].Schema
+
+	previousRequired, previousOK := requiredFieldSet(previous)
+	candidateRequired, candidateOK := requiredFieldSet(candidate)
+	if !previousOK || !candidateOK {
+		return true
+	}
+
+	switch level {
+	case CompatibilityBackward:
+		// New required fields the old data wouldn't have are incompatible.
+		return candidateRequired.isSubsetOf(previousRequired)
+	case CompatibilityForward:
+		// Removing a field old consumers rely on is incompatible.
+		return previousRequired.isSubsetOf(candidateRequired)
+	case CompatibilityFull:
+		return candidateRequired.isSubsetOf(previousRequired) && previousRequired.isSubsetOf(candidateRequired)
+	default:
+		return true
+	}
+}
+
+type stringSet map[string]struct{}
+
+func (s stringSet) isSubsetOf(other stringSet) bool {
+	for key := range s {
+		if _, ok := other[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// requiredFieldSet extracts the "required" array of an Avro/JSONSchema style
+// schema document. ok is false when the schema isn't shaped like one, in
+// which case the caller should skip the compatibility check rather than
+// guess.
+func requiredFieldSet(schema string) (stringSet, bool) {
+	var document struct {
+		Required []string `json:"required"`
+	}
+	if err := json.Unmarshal([]byte(schema), &document); err != nil {
+		return nil, false
+	}
+	set := make(stringSet, len(document.Required))
+	for _, field := range document.Required {
+		set[field] = struct{}{}
+	}
+	return set, true
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, code int, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"error_code": code,
+		"message":    message,
+	})
+}
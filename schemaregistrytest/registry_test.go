@@ -0,0 +1,43 @@
+package schemaregistrytest
+
+import (
+	"testing"
+
+	"github.com/riferrei/srclient"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeRegistryRegisterAndFetch(t *testing.T) {
+	registry := NewFakeRegistry(t)
+	client := srclient.CreateSchemaRegistryClient(registry.Server.URL)
+
+	schema := `{"type":"object","title":"Key","properties":{"field":{"type":"string"}},"required":["field"]}`
+	created, err := client.CreateSchema("test-value", schema, srclient.Json)
+	assert.Nil(t, err)
+	assert.NotZero(t, created.ID())
+
+	byVersion, err := client.GetSchemaByVersion("test-value", created.Version())
+	assert.Nil(t, err)
+	assert.Equal(t, schema, byVersion.Schema())
+
+	byID, err := client.GetSchema(created.ID())
+	assert.Nil(t, err)
+	assert.Equal(t, schema, byID.Schema())
+
+	latest, err := client.GetLatestSchema("test-value")
+	assert.Nil(t, err)
+	assert.Equal(t, created.ID(), latest.ID())
+}
+
+func TestFakeRegistryBackwardCompatibility(t *testing.T) {
+	registry := NewFakeRegistry(t)
+	client := srclient.CreateSchemaRegistryClient(registry.Server.URL)
+
+	compatible := `{"type":"object","title":"Key","properties":{"field":{"type":"string"}},"required":["field"]}`
+	_, err := client.CreateSchema("compat-value", compatible, srclient.Json)
+	assert.Nil(t, err)
+
+	incompatible := `{"type":"object","title":"Key","properties":{"field":{"type":"string"},"extra":{"type":"string"}},"required":["field","extra"]}`
+	_, err = client.CreateSchema("compat-value", incompatible, srclient.Json)
+	assert.NotNil(t, err)
+}
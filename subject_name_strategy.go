@@ -0,0 +1,165 @@
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/jhump/protoreflect/desc/protoparse"
+	"github.com/riferrei/srclient"
+)
+
+// Schema bundles a schema document with its type, so a SubjectNameStrategy
+// can pick the right record-name extraction rules instead of guessing the
+// payload format from its syntax.
+type Schema struct {
+	Schema     string
+	SchemaType srclient.SchemaType
+}
+
+// SubjectNameStrategy derives the Schema Registry subject name for a topic,
+// key/value element and schema. Register custom strategies with
+// RegisterSubjectNameStrategy so JS tests can script non-standard naming.
+type SubjectNameStrategy interface {
+	SubjectName(topic string, element Element, schema Schema) (string, *Xk6KafkaError)
+}
+
+type topicNameStrategy struct{}
+
+func (topicNameStrategy) SubjectName(topic string, element Element, _ Schema) (string, *Xk6KafkaError) {
+	return topic + "-" + string(element), nil
+}
+
+type recordNameStrategy struct{}
+
+func (recordNameStrategy) SubjectName(_ string, _ Element, schema Schema) (string, *Xk6KafkaError) {
+	return schemaRecordName(schema)
+}
+
+type topicRecordNameStrategy struct{}
+
+func (topicRecordNameStrategy) SubjectName(topic string, _ Element, schema Schema) (string, *Xk6KafkaError) {
+	name, err := schemaRecordName(schema)
+	if err != nil {
+		return "", err
+	}
+	return topic + "-" + name, nil
+}
+
+var (
+	subjectNameStrategiesMutex sync.RWMutex
+	subjectNameStrategies      = map[string]SubjectNameStrategy{
+		TopicNameStrategy:       topicNameStrategy{},
+		RecordNameStrategy:      recordNameStrategy{},
+		TopicRecordNameStrategy: topicRecordNameStrategy{},
+	}
+)
+
+// RegisterSubjectNameStrategy installs a custom SubjectNameStrategy under
+// name, so it can be selected anywhere a subjectNameStrategy string is
+// accepted.
+func RegisterSubjectNameStrategy(name string, strategy SubjectNameStrategy) {
+	subjectNameStrategiesMutex.Lock()
+	defer subjectNameStrategiesMutex.Unlock()
+	subjectNameStrategies[name] = strategy
+}
+
+// SubjectNameStrategyFunc adapts a plain function into a SubjectNameStrategy,
+// using only JS-safe argument and return types (strings, not the Schema
+// struct) so it can be called directly with the arguments a goja function
+// value receives. module.go is expected to expose
+// kafka.registerSubjectNameStrategy(name, fn) by wrapping the JS function fn
+// in a SubjectNameStrategyFunc and passing it to RegisterSubjectNameStrategy;
+// that wiring isn't part of this change, since module.go isn't in this tree.
+type SubjectNameStrategyFunc func(topic string, element Element, schema string, schemaType string) (string, error)
+
+// SubjectName implements SubjectNameStrategy by calling f with the schema's
+// type as a plain string, since srclient.SchemaType isn't a JS-visible type.
+func (f SubjectNameStrategyFunc) SubjectName(topic string, element Element, schema Schema) (string, *Xk6KafkaError) {
+	name, err := f(topic, element, schema.Schema, string(schema.SchemaType))
+	if err != nil {
+		return "", NewXk6KafkaError(failedEncodeToAvro, "Custom subject name strategy failed", err)
+	}
+	return name, nil
+}
+
+// GetSubjectNameStrategyByName resolves name to a registered
+// SubjectNameStrategy, defaulting to TopicNameStrategy when name is empty.
+func GetSubjectNameStrategyByName(name string) (SubjectNameStrategy, *Xk6KafkaError) {
+	if name == "" {
+		name = TopicNameStrategy
+	}
+
+	subjectNameStrategiesMutex.RLock()
+	defer subjectNameStrategiesMutex.RUnlock()
+
+	strategy, ok := subjectNameStrategies[name]
+	if !ok {
+		return nil, NewXk6KafkaError(failedEncodeToAvro, fmt.Sprintf(
+			"Unknown subject name strategy: %v", name), nil)
+	}
+	return strategy, nil
+}
+
+// schemaRecordName extracts the fully-qualified record name from schema,
+// using the extraction rules for its SchemaType: Avro namespace+name,
+// Protobuf message full name from its descriptor, or JSONSchema $id/title.
+func schemaRecordName(schema Schema) (string, *Xk6KafkaError) {
+	if schema.SchemaType == srclient.Protobuf {
+		return protobufRecordName(schema.Schema)
+	}
+	return jsonLikeRecordName(schema.Schema)
+}
+
+// jsonLikeRecordName handles Avro and JSONSchema documents, both of which are
+// JSON objects. Avro identifies a record with "namespace"+"name"; JSONSchema
+// identifies it with "$id" or "title".
+func jsonLikeRecordName(schema string) (string, *Xk6KafkaError) {
+	var schemaMap map[string]interface{}
+	if err := json.Unmarshal([]byte(schema), &schemaMap); err != nil {
+		return "", NewXk6KafkaError(failedToUnmarshalSchema, "Failed to unmarshal schema", nil)
+	}
+
+	if id, ok := stringField(schemaMap, "$id"); ok {
+		return id, nil
+	}
+	if title, ok := stringField(schemaMap, "title"); ok {
+		return title, nil
+	}
+
+	name, _ := stringField(schemaMap, "name")
+	if namespace, ok := stringField(schemaMap, "namespace"); ok && namespace != "" {
+		name = namespace + "." + name
+	}
+	if name == "" {
+		return "", NewXk6KafkaError(failedTypeCast, "Failed to determine record name from schema", nil)
+	}
+	return name, nil
+}
+
+func stringField(schemaMap map[string]interface{}, field string) (string, bool) {
+	value, ok := schemaMap[field]
+	if !ok {
+		return "", false
+	}
+	stringValue, ok := value.(string)
+	return stringValue, ok
+}
+
+// protobufRecordName parses the Protobuf schema text and returns the fully
+// qualified name of its first message type.
+func protobufRecordName(schema string) (string, *Xk6KafkaError) {
+	parser := protoparse.Parser{
+		Accessor: protoparse.FileContentsFromMap(map[string]string{"schema.proto": schema}),
+	}
+	descriptors, err := parser.ParseFiles("schema.proto")
+	if err != nil || len(descriptors) == 0 {
+		return "", NewXk6KafkaError(failedTypeCast, "Failed to parse Protobuf descriptor", err)
+	}
+
+	messageTypes := descriptors[0].GetMessageTypes()
+	if len(messageTypes) == 0 {
+		return "", NewXk6KafkaError(failedTypeCast, "Protobuf schema has no message types", nil)
+	}
+	return messageTypes[0].GetFullyQualifiedName(), nil
+}